@@ -11,52 +11,216 @@ consider a web application that allows its users to download a command-line
 client to interact with it. The client may need to be configured with such
 things as the user name or an access token. Using bespoke you can create a binary
 that is specifically configured for each user who downloads it.
+
+Signing
+
+Because a bespoke binary is just an executable with a zip archive appended to it,
+anyone who can write to the distributed file can replace its packaged data. Passing
+WithSigner to WithMap, WithFile or WithJSON embeds a detached signature over that
+constructor's primary payload entry, which the running binary can check at startup
+with VerifyPrimarySelf. The name is deliberate: signing covers only that single
+entry, not the executable itself, so a substituted binary with its original
+.bespoke.json and .bespoke.sig still verifies. It is not available at all on
+WithFS (a tree has no single payload to sign) or on files added afterwards with
+AddFile, AddDir or AddReader.
+
+Streaming
+
+WithMap and WithFile buffer the whole executable in memory, which is fine for
+command-line tools but wasteful for something like an HTTP server handing out a
+large binary to many concurrent clients. NewStreaming builds a Bespoke binary
+that writes the executable and the appended archive directly to an io.Writer
+as it goes, without ever holding the full executable in memory.
+
+File trees
+
+WithMap and WithFile only let you package a single map or a single file.
+WithFS packages an entire fs.FS, preserving relative paths, modes and
+modification times, and the running binary can read it back with OpenFS.
+AddFile, AddDir and AddReader offer the same capability one entry at a time
+for callers building up a Bespoke by hand.
+
+Compression
+
+Entries are stored uncompressed by default, which keeps packing fast and
+avoids the CPU cost of inflating at binary startup. For large bundled
+payloads (TLS bundles, asset trees for embedded UIs) the Compressed option
+switches entries to zip.Deflate, trading pack-time CPU and a small amount of
+Read latency for a smaller binary.
+
+Typed configuration
+
+Map and WithMap force callers to stringify every value. WithJSON packages an
+arbitrary JSON-serializable struct instead, optionally validated at pack
+time with WithValidator, and Unmarshal decodes it back in the running
+binary. WithJSON and WithMap share the same archive entry, so a binary can
+be read with either Map or Unmarshal depending on how it was packaged.
+
+Inspecting packaged binaries
+
+Map, Unmarshal, OpenFS and VerifyPrimarySelf all act on the currently running
+binary. Open, in contrast, inspects an arbitrary bespoke binary on disk -
+useful for debugging why a distributed binary misbehaves without having to
+run it. See the Reader type and the `bespoke inspect`/`extract`/`strip`
+subcommands in cmd/bespoke.
 */
 package bespoke
 
 import (
 	"archive/zip"
 	"bytes"
-	"encoding/binary"
+	"compress/flate"
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
 	"encoding/json"
 	"errors"
 	"github.com/kardianos/osext"
 	"io"
+	"io/fs"
 	"io/ioutil"
 	"math"
+	"os"
 	"path"
+	"path/filepath"
+	"strconv"
 	"time"
 )
 
 const (
-	mapFilename = ".bespoke.json"
+	mapFilename    = ".bespoke.json"
+	sigFilename    = ".bespoke.sig"
+	lengthFilename = ".bespoke.len" // records exeLength, so Open can find the executable without it
+	fsPrefix       = "fs"           // archive path prefix under which WithFS stores its tree
 
-	// Offset values in the archive are uint32, and are of the form size(executable)+n
 	// Restricting the executable size to 2^31 should be good enough.
 	maxExecutableSize = math.MaxUint32 / 2
-
-	directoryEndSignature = 0x06054b50
-
-	// Offsets inside the EOCD table
-	nDirRecordsOffset = 10 // offset of total number of central directory records)
-	startOffset       = 16 // offset of start of central directory
-
-	// Offsets inside a central directory file header
-	filenameLengthOffset   = 28
-	extraFieldLengthOffset = 30
-	commentLengthOffset    = 32
-	fhOffset               = 42
-	fhFixedSize            = 46 // Size of the non-variable parts
 )
 
+// IsInternalEntry reports whether name is one of bespoke's own bookkeeping
+// entries (the length and signature records) rather than a file packaged by
+// the caller.
+func IsInternalEntry(name string) bool {
+	switch name {
+	case lengthFilename, sigFilename:
+		return true
+	default:
+		return false
+	}
+}
+
 // Bespoke represents a packaged bespoke binary. It is created by the functions
-// bespoke.WithMap() and bespoke.WithFile(). It acts as an io.Reader and the contents
-// of the bespoke binary can be accessed through Read().
+// bespoke.WithMap(), bespoke.WithFile() and bespoke.NewStreaming(). In buffered
+// mode (WithMap, WithFile) it acts as an io.Reader and io.WriterTo and the
+// contents of the bespoke binary can be accessed through Read(). In streaming
+// mode (NewStreaming) the binary is written directly to the underlying
+// io.Writer as Finalize runs, and Read is not usable.
 type Bespoke struct {
-	buffer    *bytes.Buffer // buffer that contains the zip archive
+	buffer    *bytes.Buffer // buffer that contains the zip archive; nil in streaming mode
 	archive   *zip.Writer   // zip archive
-	exeLength uint32        // length of the executable that's stored at the beginning of buffer
-	finalized bool          // whether Close() has been called on archive
+	exeLength int64         // length of the executable that precedes the archive
+	finalized bool          // whether Finalize() has been called
+
+	primary     []byte        // content of the primary payload entry, signed when signer is set
+	primaryName string        // archive name of the primary payload entry, stored alongside the signature
+	signer      crypto.Signer // optional signer used to sign primary at finalize time
+	keyID       string        // optional identifier for signer, stored alongside the signature
+	method      uint16        // zip compression method for entries added after the option is set (default zip.Store)
+	schema      string        // schema identifier for a WithJSON payload
+	validator   Validator     // optional validator run against a WithJSON payload before packaging
+}
+
+// Option configures a Bespoke binary at creation time. Options are passed to
+// WithMap, WithFile, WithFS and WithJSON.
+type Option func(*Bespoke)
+
+// WithSigner makes WithMap/WithFile/WithJSON sign the packaged data with priv and embed
+// the detached signature as an additional archive entry. The signature can
+// later be checked by the running binary with VerifyPrimarySelf.
+func WithSigner(priv crypto.Signer) Option {
+	return func(b *Bespoke) {
+		b.signer = priv
+	}
+}
+
+// WithKeyID attaches an identifier for the signing key to the embedded
+// signature, so that an operator inspecting the binary can tell which key is
+// expected to have produced it. VerifyPrimarySelf does not check it.
+func WithKeyID(id string) Option {
+	return func(b *Bespoke) {
+		b.keyID = id
+	}
+}
+
+// Compressed makes entries added after this option is applied use the given
+// zip compression method (zip.Store or zip.Deflate) instead of the default
+// zip.Store.
+func Compressed(method uint16) Option {
+	return func(b *Bespoke) {
+		b.method = method
+	}
+}
+
+// CompressionLevel sets the compress/flate compression level used for
+// entries stored with Compressed(zip.Deflate). It has no effect on its own;
+// pass it alongside Compressed(zip.Deflate).
+func CompressionLevel(level int) Option {
+	return func(b *Bespoke) {
+		b.archive.RegisterCompressor(zip.Deflate, func(w io.Writer) (io.WriteCloser, error) {
+			return flate.NewWriter(w, level)
+		})
+	}
+}
+
+// RegisterCompressor registers a custom compressor for method on the
+// Bespoke's underlying archive, as per (*zip.Writer).RegisterCompressor.
+func RegisterCompressor(method uint16, comp zip.Compressor) Option {
+	return func(b *Bespoke) {
+		b.archive.RegisterCompressor(method, comp)
+	}
+}
+
+// Validator validates the JSON-encoded payload passed to WithJSON before it
+// is packaged, e.g. against a JSON Schema. Validate returns a non-nil error
+// to abort packaging.
+type Validator interface {
+	Validate(data []byte) error
+}
+
+// WithValidator sets a Validator that WithJSON runs against the marshaled
+// payload before packaging it.
+func WithValidator(v Validator) Option {
+	return func(b *Bespoke) {
+		b.validator = v
+	}
+}
+
+// WithSchema attaches a schema identifier to a WithJSON payload, stored
+// alongside the data so the running binary (or an operator inspecting the
+// binary) knows which schema to expect.
+func WithSchema(name string) Option {
+	return func(b *Bespoke) {
+		b.schema = name
+	}
+}
+
+// jsonPayload is the shape WithJSON writes to mapFilename. The "schema"
+// field distinguishes it from the plain map written by WithMap, which has
+// no such field.
+type jsonPayload struct {
+	Schema string          `json:"schema"`
+	Data   json.RawMessage `json:"data"`
+}
+
+// signature is the JSON structure stored in sigFilename. Entry records which
+// archive entry was signed, since it varies by constructor (mapFilename for
+// WithMap/WithJSON, the base name of the packaged file for WithFile).
+type signature struct {
+	KeyID     string `json:"keyid,omitempty"`
+	Entry     string `json:"entry"`
+	Signature []byte `json:"signature"`
 }
 
 func newBespoke(exe io.Reader) (*Bespoke, error) {
@@ -69,20 +233,43 @@ func newBespoke(exe io.Reader) (*Bespoke, error) {
 	if n > maxExecutableSize {
 		return nil, errors.New("executables larger than 2^31 bytes not supported")
 	}
+
 	archive := zip.NewWriter(buffer)
+	archive.SetOffset(n)
 
 	return &Bespoke{
 		buffer:    buffer,
 		archive:   archive,
-		exeLength: uint32(n),
-		finalized: false,
+		exeLength: n,
 	}, nil
 }
 
-func (b *Bespoke) addBuffer(p []byte, filename string) error {
-	fh := &zip.FileHeader{Name: filename}
-	fh.SetModTime(time.Now())
-	fh.SetMode(0644)
+// NewStreaming creates a Bespoke binary that writes exe (exeSize bytes, read
+// through exe) followed by the appended archive directly to out, without
+// buffering the executable in memory. Use AddFile, AddDir or AddReader to
+// add payload entries, then call Finalize to stream the archive to out.
+func NewStreaming(exe io.ReaderAt, exeSize int64, out io.Writer) (*Bespoke, error) {
+	if exeSize > maxExecutableSize {
+		return nil, errors.New("executables larger than 2^31 bytes not supported")
+	}
+
+	if _, err := io.Copy(out, io.NewSectionReader(exe, 0, exeSize)); err != nil {
+		return nil, err
+	}
+
+	archive := zip.NewWriter(out)
+	archive.SetOffset(exeSize)
+
+	return &Bespoke{
+		archive:   archive,
+		exeLength: exeSize,
+	}, nil
+}
+
+func (b *Bespoke) addEntry(fh *zip.FileHeader, p []byte) error {
+	if b.method != 0 {
+		fh.Method = b.method
+	}
 
 	w, err := b.archive.CreateHeader(fh)
 	if err != nil {
@@ -97,6 +284,14 @@ func (b *Bespoke) addBuffer(p []byte, filename string) error {
 	return nil
 }
 
+func (b *Bespoke) addBuffer(p []byte, filename string) error {
+	fh := &zip.FileHeader{Name: filename}
+	fh.SetModTime(time.Now())
+	fh.SetMode(0644)
+
+	return b.addEntry(fh, p)
+}
+
 func (b *Bespoke) addFile(p string) error {
 	filename := path.Base(p)
 
@@ -105,94 +300,164 @@ func (b *Bespoke) addFile(p string) error {
 		return err
 	}
 
+	b.primary = content
+	b.primaryName = filename
 	return b.addBuffer(content, filename)
 }
 
-// Write the archive to the buffer and fix up offsets.
-func (b *Bespoke) finalize() error {
-	if err := b.archive.Close(); err != nil {
+// AddFile adds the file at path to the archive under the given archive-relative
+// name, preserving its mode and modification time. Unlike WithFile, it does not
+// mark the entry as the primary signed payload. Call Finalize once all entries
+// have been added.
+func (b *Bespoke) AddFile(name string, path string) error {
+	info, err := os.Stat(path)
+	if err != nil {
 		return err
 	}
 
-	if err := b.fixOffsets(); err != nil {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
 		return err
 	}
-	b.finalized = true
 
-	return nil
+	fh, err := zip.FileInfoHeader(info)
+	if err != nil {
+		return err
+	}
+	fh.Name = name
+
+	return b.addEntry(fh, content)
 }
 
-var le = binary.LittleEndian
+// AddDir walks dir and adds every file and subdirectory under it to the
+// archive, with archive names of the form path.Join(prefix, rel), preserving
+// relative paths, modes and modification times. Call Finalize once all
+// entries have been added.
+func (b *Bespoke) AddDir(prefix string, dir string) error {
+	return filepath.Walk(dir, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if p == dir {
+			return nil
+		}
 
-// Return the offset of the end of central directory table.
-func findEocdOffset(b []byte) int64 {
-	sigBytes := make([]byte, 4)
-	le.PutUint32(sigBytes, directoryEndSignature)
+		rel, err := filepath.Rel(dir, p)
+		if err != nil {
+			return err
+		}
+		name := path.Join(prefix, filepath.ToSlash(rel))
 
-	for i := len(b) - 4; i > 0; i-- {
-		if b[i] == sigBytes[0] &&
-			b[i+1] == sigBytes[1] &&
-			b[i+2] == sigBytes[2] &&
-			b[i+3] == sigBytes[3] {
-			return int64(i)
+		if info.IsDir() {
+			fh, err := zip.FileInfoHeader(info)
+			if err != nil {
+				return err
+			}
+			fh.Name = name
+			return b.addEntry(fh, nil)
 		}
-	}
 
-	return -1
+		return b.AddFile(name, p)
+	})
 }
 
-// Return the size of the central directory file header record at b[off]
-func fhRecordSize(b []byte, off uint32) uint32 {
-	filenameLength := le.Uint16(b[off+filenameLengthOffset : off+filenameLengthOffset+2])
-	extraLength := le.Uint16(b[off+extraFieldLengthOffset : off+extraFieldLengthOffset+2])
-	commentLength := le.Uint16(b[off+commentLengthOffset : off+commentLengthOffset+2])
+// AddReader adds content read from r to the archive under the given
+// archive-relative name, stamped with the current time. Call Finalize once
+// all entries have been added.
+func (b *Bespoke) AddReader(name string, r io.Reader) error {
+	content, err := ioutil.ReadAll(r)
+	if err != nil {
+		return err
+	}
 
-	return uint32(fhFixedSize + filenameLength + extraLength + commentLength)
+	return b.addBuffer(content, name)
 }
 
-// The offsets of files within the archive are wrong because we've prepended
-// the executable. So add exeLength to every offset.
-//
-// This is equivalent to the --adjust-sfx option to the zip utility.
-func (b *Bespoke) fixOffsets() error {
-	buf := b.buffer.Bytes()
-	eocd := findEocdOffset(buf)
-	if eocd == -1 {
-		return errors.New("couldn't find EOCD in archive")
+// addSignature signs b.primary with b.signer and adds the signature as an
+// archive entry named sigFilename.
+func (b *Bespoke) addSignature() error {
+	var opts crypto.SignerOpts
+	message := b.primary
+
+	if _, ok := b.signer.Public().(ed25519.PublicKey); ok {
+		opts = crypto.Hash(0)
+	} else {
+		digest := sha256.Sum256(b.primary)
+		message = digest[:]
+		opts = &rsa.PSSOptions{Hash: crypto.SHA256, SaltLength: rsa.PSSSaltLengthAuto}
 	}
 
-	nOff := eocd + nDirRecordsOffset
-	n := int(le.Uint16(buf[nOff : nOff+2]))
+	sig, err := b.signer.Sign(rand.Reader, message, opts)
+	if err != nil {
+		return err
+	}
 
-	start := le.Uint32(buf[eocd+startOffset : eocd+startOffset+4])
-	start += b.exeLength
-	le.PutUint32(buf[eocd+startOffset:eocd+startOffset+4], start)
+	content, err := json.Marshal(signature{KeyID: b.keyID, Entry: b.primaryName, Signature: sig})
+	if err != nil {
+		return err
+	}
 
-	h := start
-	for i := 0; i < n; i++ {
-		offset := le.Uint32(buf[h+fhOffset : h+fhOffset+4])
-		offset += b.exeLength
-		le.PutUint32(buf[h+fhOffset:h+fhOffset+4], offset)
+	return b.addBuffer(content, sigFilename)
+}
 
-		h += fhRecordSize(buf, h)
+// Finalize writes any pending signature, closes the archive and, in
+// streaming mode, flushes the remaining archive bytes to the underlying
+// io.Writer. Because the zip writer's offset was set up front with
+// SetOffset, the local file header and central directory offsets are
+// correct the first time they're written; no fix-up pass is needed.
+func (b *Bespoke) Finalize() error {
+	if err := b.addBuffer([]byte(strconv.FormatInt(b.exeLength, 10)), lengthFilename); err != nil {
+		return err
+	}
+
+	if b.signer != nil {
+		if err := b.addSignature(); err != nil {
+			return err
+		}
+	}
+
+	if err := b.archive.Close(); err != nil {
+		return err
 	}
+	b.finalized = true
+
 	return nil
 }
 
 // Read reads the next len(p) bytes from the buffer or until the bespoke binary is drained.
 // The return value n is the number of bytes read.
 // If the binary has no data to return, err is io.EOF (unless len(p) is zero); otherwise it is nil.
+// Read is only usable in buffered mode (WithMap, WithFile); in streaming mode
+// the binary is written directly to the underlying io.Writer as Finalize runs.
 func (b *Bespoke) Read(p []byte) (int, error) {
 	if !b.finalized {
-		panic("read attempted without calling finalize")
+		panic("read attempted without calling Finalize")
+	}
+	if b.buffer == nil {
+		return 0, errors.New("bespoke: Read is not supported on a streaming Bespoke")
 	}
 
 	return b.buffer.Read(p)
 }
 
+// WriteTo writes the bespoke binary to w. It implements io.WriterTo, which
+// lets io.Copy stream the binary to w without an intermediate copy. Like
+// Read, it is only usable in buffered mode.
+func (b *Bespoke) WriteTo(w io.Writer) (int64, error) {
+	if !b.finalized {
+		panic("WriteTo attempted without calling Finalize")
+	}
+	if b.buffer == nil {
+		return 0, errors.New("bespoke: WriteTo is not supported on a streaming Bespoke")
+	}
+
+	return b.buffer.WriteTo(w)
+}
+
 // WithMap creates a bespoke binary from the executable exe and the given
-// map. The executable can access the map by calling bespoke.Map()
-func WithMap(exe io.Reader, data map[string]string) (*Bespoke, error) {
+// map. The executable can access the map by calling bespoke.Map(). Options
+// such as WithSigner can be passed to sign the packaged data.
+func WithMap(exe io.Reader, data map[string]string, opts ...Option) (*Bespoke, error) {
 	content, err := json.Marshal(data)
 	if err != nil {
 		return nil, err
@@ -203,11 +468,17 @@ func WithMap(exe io.Reader, data map[string]string) (*Bespoke, error) {
 		return nil, err
 	}
 
+	for _, opt := range opts {
+		opt(b)
+	}
+
+	b.primary = content
+	b.primaryName = mapFilename
 	if err := b.addBuffer(content, mapFilename); err != nil {
 		return nil, err
 	}
 
-	if err := b.finalize(); err != nil {
+	if err := b.Finalize(); err != nil {
 		return nil, err
 	}
 
@@ -215,26 +486,138 @@ func WithMap(exe io.Reader, data map[string]string) (*Bespoke, error) {
 }
 
 // WithFile creates a bespoke binary from the executable exe and the given
-// file. The executable can access the file by calling bespoke.ReadFile()
-func WithFile(exe io.Reader, filePath string) (*Bespoke, error) {
+// file. The executable can access the file by calling bespoke.ReadFile().
+// Options such as WithSigner can be passed to sign the packaged data.
+func WithFile(exe io.Reader, filePath string, opts ...Option) (*Bespoke, error) {
 	b, err := newBespoke(exe)
 	if err != nil {
 		return nil, err
 	}
 
+	for _, opt := range opts {
+		opt(b)
+	}
+
 	if err := b.addFile(filePath); err != nil {
 		return nil, err
 	}
 
-	if err := b.finalize(); err != nil {
+	if err := b.Finalize(); err != nil {
 		return nil, err
 	}
 
 	return b, nil
 }
 
-func readFile(z *zip.ReadCloser, name string) ([]byte, error) {
-	for _, f := range z.File {
+// WithFS creates a bespoke binary from the executable exe and every file in
+// fsys, preserving relative paths, modes and modification times. The
+// executable can access the tree by calling bespoke.OpenFS(). Options such
+// as Compressed can be passed to control how the tree's entries are stored.
+// WithSigner is not supported here: signing covers a single primary entry
+// (see WithMap, WithFile, WithJSON), and a file tree has no such entry.
+func WithFS(exe io.Reader, fsys fs.FS, opts ...Option) (*Bespoke, error) {
+	b, err := newBespoke(exe)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, opt := range opts {
+		opt(b)
+	}
+
+	if b.signer != nil {
+		return nil, errors.New("bespoke: WithSigner is not supported with WithFS")
+	}
+
+	err = fs.WalkDir(fsys, ".", func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if p == "." {
+			return nil
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+
+		fh, err := zip.FileInfoHeader(info)
+		if err != nil {
+			return err
+		}
+		fh.Name = path.Join(fsPrefix, p)
+
+		if d.IsDir() {
+			fh.Name += "/"
+			return b.addEntry(fh, nil)
+		}
+
+		content, err := fs.ReadFile(fsys, p)
+		if err != nil {
+			return err
+		}
+		return b.addEntry(fh, content)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := b.Finalize(); err != nil {
+		return nil, err
+	}
+
+	return b, nil
+}
+
+// WithJSON creates a bespoke binary from the executable exe and an
+// arbitrary JSON-serializable value v. The executable can access it with
+// bespoke.Unmarshal. WithSchema attaches a schema identifier and
+// WithValidator runs a Validator against the marshaled data before
+// packaging. WithJSON coexists with WithMap/Map: both share the mapFilename
+// entry, distinguished by the top-level "schema" field that only a WithJSON
+// payload has.
+func WithJSON(exe io.Reader, v any, opts ...Option) (*Bespoke, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	b, err := newBespoke(exe)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, opt := range opts {
+		opt(b)
+	}
+
+	if b.validator != nil {
+		if err := b.validator.Validate(data); err != nil {
+			return nil, err
+		}
+	}
+
+	content, err := json.Marshal(jsonPayload{Schema: b.schema, Data: data})
+	if err != nil {
+		return nil, err
+	}
+
+	b.primary = content
+	b.primaryName = mapFilename
+	if err := b.addBuffer(content, mapFilename); err != nil {
+		return nil, err
+	}
+
+	if err := b.Finalize(); err != nil {
+		return nil, err
+	}
+
+	return b, nil
+}
+
+func readFile(files []*zip.File, name string) ([]byte, error) {
+	for _, f := range files {
 		if f.Name == name {
 			file, err := f.Open()
 			if err != nil {
@@ -278,7 +661,7 @@ func ReadFile(name string) ([]byte, error) {
 	}
 	defer self.Close()
 
-	return readFile(self, mapFilename)
+	return readFile(self.File, name)
 }
 
 // Map returns the string->string map that was packaged with the currently
@@ -290,7 +673,7 @@ func Map() (map[string]string, error) {
 	}
 	defer self.Close()
 
-	content, err := readFile(self, mapFilename)
+	content, err := readFile(self.File, mapFilename)
 	if err != nil {
 		return nil, err
 	}
@@ -303,3 +686,92 @@ func Map() (map[string]string, error) {
 
 	return m, nil
 }
+
+// Unmarshal decodes the JSON value that was packaged with the currently
+// executing binary via WithJSON into v. It throws an error if this is not a
+// bespoke binary or it was not packaged with WithJSON.
+func Unmarshal(v any) error {
+	self, err := openSelf()
+	if err != nil {
+		return err
+	}
+	defer self.Close()
+
+	content, err := readFile(self.File, mapFilename)
+	if err != nil {
+		return err
+	}
+
+	var payload jsonPayload
+	if err := json.Unmarshal(content, &payload); err != nil {
+		return err
+	}
+	if payload.Data == nil {
+		return errors.New("bespoke: binary was not packaged with WithJSON")
+	}
+
+	return json.Unmarshal(payload.Data, v)
+}
+
+// OpenFS returns the file tree that was packaged with the currently executing
+// binary by WithFS, rooted at the top of that tree. It throws an error if
+// this is not a bespoke binary or it was not packaged with WithFS. The
+// underlying archive is kept open for the lifetime of the returned fs.FS.
+func OpenFS() (fs.FS, error) {
+	self, err := openSelf()
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := fs.Stat(self, fsPrefix); err != nil {
+		self.Close()
+		return nil, errors.New("bespoke: binary was not packaged with WithFS")
+	}
+
+	return fs.Sub(self, fsPrefix)
+}
+
+// VerifyPrimarySelf checks the detached signature embedded in the currently
+// executing binary against pub. It verifies only the primary payload entry
+// (the map, file or JSON value passed to WithMap, WithFile or WithJSON) -
+// not the executable itself or any entries added with AddFile, AddDir or
+// AddReader. It returns an error if the binary was not signed, the
+// signature is malformed, or verification fails.
+func VerifyPrimarySelf(pub crypto.PublicKey) error {
+	self, err := openSelf()
+	if err != nil {
+		return err
+	}
+	defer self.Close()
+
+	sigContent, err := readFile(self.File, sigFilename)
+	if err != nil {
+		return err
+	}
+
+	var sig signature
+	if err := json.Unmarshal(sigContent, &sig); err != nil {
+		return err
+	}
+
+	content, err := readFile(self.File, sig.Entry)
+	if err != nil {
+		return err
+	}
+
+	switch key := pub.(type) {
+	case ed25519.PublicKey:
+		if !ed25519.Verify(key, content, sig.Signature) {
+			return errors.New("bespoke: signature verification failed")
+		}
+	case *rsa.PublicKey:
+		digest := sha256.Sum256(content)
+		if err := rsa.VerifyPSS(key, crypto.SHA256, digest[:], sig.Signature, nil); err != nil {
+			return errors.New("bespoke: signature verification failed: " + err.Error())
+		}
+	default:
+		return errors.New("bespoke: unsupported public key type")
+	}
+
+	return nil
+}
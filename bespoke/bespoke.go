@@ -1,51 +1,233 @@
 /*
-Command bespoke provides a way to create bespoke binaries.
+Command bespoke provides a way to create and inspect bespoke binaries.
 
 Example
 
 bespoke can be invoked as:
 
-  $ bespoke -name=world examples/hello/hello hello_world
+  $ bespoke pack -name=world examples/hello/hello hello_world
   $ ./hello_world
   hello world
 
+  $ bespoke inspect hello_world
+  $ bespoke extract hello_world /tmp/hello_world-contents
+  $ bespoke strip hello_world
+
 */
 package main
 
 import (
+	"archive/zip"
+	"encoding/json"
+	"errors"
 	"flag"
+	"fmt"
 	"github.com/vikasgorur/bespoke"
 	"io"
 	"os"
+	"path/filepath"
 )
 
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: bespoke <pack|inspect|extract|strip> [arguments]")
+}
+
 func main() {
-	var name = flag.String("name", "vikas", "name to add to the executable")
-	flag.Parse()
-	args := flag.Args()
+	if len(os.Args) < 2 {
+		usage()
+		os.Exit(1)
+	}
 
-	if len(args) != 2 {
-		flag.Usage()
+	var err error
+	switch os.Args[1] {
+	case "pack":
+		err = pack(os.Args[2:])
+	case "inspect":
+		err = inspect(os.Args[2:])
+	case "extract":
+		err = extract(os.Args[2:])
+	case "strip":
+		err = strip(os.Args[2:])
+	default:
+		usage()
+		os.Exit(1)
+	}
+
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "bespoke: "+err.Error())
+		os.Exit(1)
+	}
+}
+
+// pack packages an executable with a name=value map, the same thing the
+// original single-purpose bespoke command did.
+func pack(args []string) error {
+	fset := flag.NewFlagSet("pack", flag.ExitOnError)
+	name := fset.String("name", "vikas", "name to add to the executable")
+	fset.Parse(args)
+
+	rest := fset.Args()
+	if len(rest) != 2 {
+		fset.Usage()
 		os.Exit(1)
 	}
 
-	exe, err := os.Open(args[0])
+	exe, err := os.Open(rest[0])
 	if err != nil {
-		panic(err.Error())
+		return err
 	}
+	defer exe.Close()
 
 	b, err := bespoke.WithMap(exe, map[string]string{"name": *name})
 	if err != nil {
-		panic(err.Error())
+		return err
 	}
 
-	out, err := os.OpenFile(args[1], os.O_CREATE|os.O_WRONLY, 0755)
+	out, err := os.OpenFile(rest[1], os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0755)
 	if err != nil {
-		panic(err.Error())
+		return err
 	}
 	defer out.Close()
 
-	if _, err := io.Copy(out, b); err != nil {
-		panic(err.Error())
+	_, err = io.Copy(out, b)
+	return err
+}
+
+// inspect prints the executable size, the embedded files with their sizes,
+// modes and CRCs, and the decoded map, if any.
+func inspect(args []string) error {
+	if len(args) != 1 {
+		return errors.New("usage: bespoke inspect <binary>")
+	}
+
+	r, err := bespoke.Open(args[0])
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	fmt.Printf("executable size: %d bytes\n", r.ExecutableSize())
+
+	fmt.Println("files:")
+	for _, f := range r.RawFiles() {
+		if bespoke.IsInternalEntry(f.Name) {
+			continue
+		}
+		fmt.Printf("  %-40s size=%-8d mode=%-10s crc32=%08x\n",
+			f.Name, f.UncompressedSize64, f.Mode(), f.CRC32)
+	}
+
+	fmt.Println("bookkeeping:")
+	for _, f := range r.RawFiles() {
+		if !bespoke.IsInternalEntry(f.Name) {
+			continue
+		}
+		fmt.Printf("  %-40s size=%-8d mode=%-10s crc32=%08x\n",
+			f.Name, f.UncompressedSize64, f.Mode(), f.CRC32)
+	}
+
+	if m, err := r.Map(); err == nil {
+		fmt.Println("map:")
+		for k, v := range m {
+			fmt.Printf("  %s=%s\n", k, v)
+		}
+	} else if raw, err := r.JSON(); err == nil {
+		var payload struct {
+			Schema string          `json:"schema"`
+			Data   json.RawMessage `json:"data"`
+		}
+		if err := json.Unmarshal(raw, &payload); err != nil {
+			return err
+		}
+		fmt.Printf("json: schema=%s\n", payload.Schema)
+		fmt.Printf("  data=%s\n", payload.Data)
+	} else {
+		fmt.Println("map: (none; not packaged with WithMap or WithJSON)")
+	}
+
+	return nil
+}
+
+// extract writes every packaged file to outdir, preserving relative paths
+// and modes. bespoke's own bookkeeping entries are not extracted.
+func extract(args []string) error {
+	if len(args) != 2 {
+		return errors.New("usage: bespoke extract <binary> <outdir>")
 	}
+
+	r, err := bespoke.Open(args[0])
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	outdir := args[1]
+	for _, f := range r.RawFiles() {
+		if bespoke.IsInternalEntry(f.Name) {
+			continue
+		}
+		dest := filepath.Join(outdir, filepath.FromSlash(f.Name))
+
+		if f.FileInfo().IsDir() {
+			if err := os.MkdirAll(dest, f.Mode()); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+			return err
+		}
+
+		if err := extractFile(f, dest); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func extractFile(f *zip.File, dest string) error {
+	rc, err := f.Open()
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+
+	out, err := os.OpenFile(dest, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, f.Mode())
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	_, err = io.Copy(out, rc)
+	return err
+}
+
+// strip writes a plain executable, with the appended archive removed, next
+// to the original binary.
+func strip(args []string) error {
+	if len(args) != 1 {
+		return errors.New("usage: bespoke strip <binary>")
+	}
+
+	r, err := bespoke.Open(args[0])
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	outPath := args[0] + ".stripped"
+	out, err := os.OpenFile(outPath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0755)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	if _, err := io.Copy(out, r.Executable()); err != nil {
+		return err
+	}
+
+	fmt.Println(outPath)
+	return nil
 }
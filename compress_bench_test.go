@@ -0,0 +1,55 @@
+package bespoke
+
+import (
+	"archive/zip"
+	"bytes"
+	"io/ioutil"
+	"testing"
+)
+
+// representativePayload returns n bytes of repetitive, JSON-like content,
+// representative of the config/asset payloads Compressed targets.
+func representativePayload(n int) []byte {
+	const line = `{"name":"vikas","token":"deadbeefdeadbeefdeadbeef","env":"production"}` + "\n"
+	buf := make([]byte, 0, n)
+	for len(buf) < n {
+		buf = append(buf, line...)
+	}
+	return buf[:n]
+}
+
+func benchmarkPack(b *testing.B, method uint16) {
+	payload := representativePayload(1 << 20)
+	exe := bytes.NewReader([]byte("#!/bin/sh\n"))
+
+	b.ReportAllocs()
+	b.SetBytes(int64(len(payload)))
+
+	for i := 0; i < b.N; i++ {
+		bp, err := NewStreaming(exe, exe.Size(), ioutil.Discard)
+		if err != nil {
+			b.Fatal(err)
+		}
+		Compressed(method)(bp)
+
+		if err := bp.AddReader("payload.json", bytes.NewReader(payload)); err != nil {
+			b.Fatal(err)
+		}
+		if err := bp.Finalize(); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkPackStore measures packing a representative payload with entries
+// stored uncompressed, the Compressed default.
+func BenchmarkPackStore(b *testing.B) {
+	benchmarkPack(b, zip.Store)
+}
+
+// BenchmarkPackDeflate measures packing the same payload with Compressed's
+// zip.Deflate option, to quantify the pack-time cost it trades for a
+// smaller binary.
+func BenchmarkPackDeflate(b *testing.B) {
+	benchmarkPack(b, zip.Deflate)
+}
@@ -0,0 +1,112 @@
+package bespoke
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"strconv"
+)
+
+// Reader gives read and inspection access to an already-packaged bespoke
+// binary on disk, as opposed to the currently running one (for which use
+// Map, Unmarshal, OpenFS or VerifyPrimarySelf). It's returned by Open.
+type Reader struct {
+	file    *os.File
+	archive *zip.Reader
+	exeSize int64
+}
+
+// Open opens the bespoke binary at path for inspection. The caller must
+// call Close when done with it.
+func Open(path string) (*Reader, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	archive, err := zip.NewReader(f, info.Size())
+	if err != nil {
+		f.Close()
+		return nil, errors.New(err.Error() + ": " + path)
+	}
+
+	lengthContent, err := readFile(archive.File, lengthFilename)
+	if err != nil {
+		f.Close()
+		return nil, errors.New("not a bespoke binary: " + path)
+	}
+
+	exeSize, err := strconv.ParseInt(string(lengthContent), 10, 64)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	return &Reader{file: f, archive: archive, exeSize: exeSize}, nil
+}
+
+// Close closes the underlying binary.
+func (r *Reader) Close() error {
+	return r.file.Close()
+}
+
+// ExecutableSize returns the size in bytes of the executable that precedes
+// the packaged archive.
+func (r *Reader) ExecutableSize() int64 {
+	return r.exeSize
+}
+
+// Executable returns a reader over the packaged executable, stopping
+// before the appended archive.
+func (r *Reader) Executable() io.ReadSeeker {
+	return io.NewSectionReader(r.file, 0, r.exeSize)
+}
+
+// Files returns file-info for every entry in the packaged archive.
+func (r *Reader) Files() []fs.FileInfo {
+	infos := make([]fs.FileInfo, len(r.archive.File))
+	for i, f := range r.archive.File {
+		infos[i] = f.FileInfo()
+	}
+	return infos
+}
+
+// RawFiles returns the packaged archive's file headers directly. Unlike
+// Files, it exposes zip-specific metadata such as CRC32 and the compression
+// method, and lets the caller read an entry's content with (*zip.File).Open.
+func (r *Reader) RawFiles() []*zip.File {
+	return r.archive.File
+}
+
+// Map returns the string->string map packaged into this binary, as per the
+// package-level Map function for the currently executing binary. It returns
+// an error if the binary was not packaged with WithMap.
+func (r *Reader) Map() (map[string]string, error) {
+	content, err := readFile(r.archive.File, mapFilename)
+	if err != nil {
+		return nil, err
+	}
+
+	var m map[string]string
+	if err := json.Unmarshal(content, &m); err != nil {
+		return nil, err
+	}
+
+	return m, nil
+}
+
+// JSON returns the raw {"schema", "data"} payload packaged into this binary
+// by WithJSON. It returns an error if the binary was not packaged with
+// WithJSON (or WithMap, whose entry it shares).
+func (r *Reader) JSON() ([]byte, error) {
+	return readFile(r.archive.File, mapFilename)
+}